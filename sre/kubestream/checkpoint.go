@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultCheckpointInterval is how often in-memory checkpoints are flushed to
+// disk when --checkpoint-dir is set.
+const defaultCheckpointInterval = 10 * time.Second
+
+// Checkpointer persists the last-seen Kubernetes log timestamp for each
+// StreamKey to disk, so that after a crash or restart streamLogs can resume
+// near where it left off instead of replaying the whole log. SinceTime only
+// gives the API server second precision, so streamLogs also filters out any
+// resumed line whose nanosecond-precision timestamp is not after the
+// checkpoint, making resume duplicate-free rather than merely "close".
+type Checkpointer struct {
+	dir string
+
+	mu       sync.Mutex
+	lastSeen map[StreamKey]time.Time
+	dirty    map[StreamKey]bool
+}
+
+func NewCheckpointer(dir string) *Checkpointer {
+	return &Checkpointer{
+		dir:      dir,
+		lastSeen: make(map[StreamKey]time.Time),
+		dirty:    make(map[StreamKey]bool),
+	}
+}
+
+// isDuplicateResumedLine reports whether a line timestamped ts was already
+// delivered before the restart that produced the resumeAfter checkpoint.
+// SinceTime truncates to second precision on the wire, so the API server may
+// re-send everything from the checkpointed second onward; this nanosecond
+// comparison is what actually makes resume duplicate-free. A zero resumeAfter
+// (no checkpoint yet) or a zero ts (unparseable line) never counts as a
+// duplicate.
+func isDuplicateResumedLine(ts, resumeAfter time.Time) bool {
+	if resumeAfter.IsZero() || ts.IsZero() {
+		return false
+	}
+	return !ts.After(resumeAfter)
+}
+
+// checkpointPath maps a StreamKey to <dir>/<namespace>/<pod>/<container>.checkpoint.
+func (c *Checkpointer) checkpointPath(key StreamKey) string {
+	parts := strings.SplitN(key.String(), "/", 3)
+	if len(parts) != 3 {
+		return filepath.Join(c.dir, key.String()+".checkpoint")
+	}
+	return filepath.Join(c.dir, parts[0], parts[1], parts[2]+".checkpoint")
+}
+
+// Load reads the last checkpointed timestamp for key, if one exists on disk.
+func (c *Checkpointer) Load(key StreamKey) (time.Time, bool) {
+	data, err := os.ReadFile(c.checkpointPath(key))
+	if err != nil {
+		return time.Time{}, false
+	}
+	ts, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(data)))
+	if err != nil {
+		log.Printf("Error parsing checkpoint for %s: %v", key, err)
+		return time.Time{}, false
+	}
+	return ts, true
+}
+
+// Update records ts as the last-seen timestamp for key, if it is newer than
+// what's already recorded. Zero timestamps (lines with no parseable prefix)
+// are ignored.
+func (c *Checkpointer) Update(key StreamKey, ts time.Time) {
+	if ts.IsZero() {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if prev, ok := c.lastSeen[key]; !ok || ts.After(prev) {
+		c.lastSeen[key] = ts
+		c.dirty[key] = true
+	}
+}
+
+// Flush writes every dirty checkpoint to disk.
+func (c *Checkpointer) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.dirty {
+		path := c.checkpointPath(key)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			log.Printf("Error creating checkpoint directory for %s: %v", key, err)
+			continue
+		}
+		if err := os.WriteFile(path, []byte(c.lastSeen[key].Format(time.RFC3339Nano)), 0o644); err != nil {
+			log.Printf("Error writing checkpoint for %s: %v", key, err)
+			continue
+		}
+		delete(c.dirty, key)
+	}
+}
+
+// Run periodically flushes checkpoints to disk until ctx is cancelled, then
+// performs one final flush before returning.
+func (c *Checkpointer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.Flush()
+			return
+		case <-ticker.C:
+			c.Flush()
+		}
+	}
+}
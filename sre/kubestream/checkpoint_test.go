@@ -0,0 +1,154 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckpointerUpdateAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	c := NewCheckpointer(dir)
+	key := NewStreamKey("default", "mypod", "mycontainer")
+
+	if _, ok := c.Load(key); ok {
+		t.Fatalf("Load on an empty checkpointer returned ok=true")
+	}
+
+	first := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.Update(key, first)
+	c.Flush()
+
+	got, ok := c.Load(key)
+	if !ok {
+		t.Fatalf("Load after Update+Flush returned ok=false")
+	}
+	if !got.Equal(first) {
+		t.Errorf("Load() = %v, want %v", got, first)
+	}
+
+	// An older timestamp must not regress the checkpoint.
+	c.Update(key, first.Add(-time.Hour))
+	c.Flush()
+	if got, _ := c.Load(key); !got.Equal(first) {
+		t.Errorf("an older Update regressed the checkpoint: got %v, want %v", got, first)
+	}
+
+	// A newer timestamp, including sub-second precision, must advance it and
+	// round-trip through disk at nanosecond precision.
+	second := first.Add(1500 * time.Millisecond)
+	c.Update(key, second)
+	c.Flush()
+	got, ok = c.Load(key)
+	if !ok || !got.Equal(second) {
+		t.Errorf("Load() after newer Update = %v, ok=%v, want %v, true", got, ok, second)
+	}
+}
+
+func TestCheckpointerUpdateIgnoresZeroTimestamp(t *testing.T) {
+	c := NewCheckpointer(t.TempDir())
+	key := NewStreamKey("default", "mypod", "mycontainer")
+
+	c.Update(key, time.Time{})
+	c.Flush()
+
+	if _, ok := c.Load(key); ok {
+		t.Fatalf("Update with a zero timestamp created a checkpoint")
+	}
+}
+
+func TestIsDuplicateResumedLine(t *testing.T) {
+	checkpoint := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name          string
+		ts            time.Time
+		resumeAfter   time.Time
+		wantDuplicate bool
+	}{
+		{"no checkpoint yet", checkpoint, time.Time{}, false},
+		{"unparseable line", time.Time{}, checkpoint, false},
+		{"same second, same instant", checkpoint, checkpoint, true},
+		{"same second, earlier nanosecond", checkpoint.Add(-time.Nanosecond), checkpoint, true},
+		{"same second, later nanosecond", checkpoint.Add(time.Nanosecond), checkpoint, false},
+		{"next second", checkpoint.Add(time.Second), checkpoint, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := isDuplicateResumedLine(c.ts, c.resumeAfter); got != c.wantDuplicate {
+				t.Errorf("isDuplicateResumedLine(%v, %v) = %v, want %v", c.ts, c.resumeAfter, got, c.wantDuplicate)
+			}
+		})
+	}
+}
+
+// TestResumeIsDuplicateFreeAcrossSecondTruncation demonstrates the bug this
+// checkpoint scheme guards against: the API server only honors SinceTime at
+// second precision, so a naive resume would re-deliver every line sharing the
+// checkpointed second. isDuplicateResumedLine is what makes resume
+// duplicate-free despite that.
+func TestResumeIsDuplicateFreeAcrossSecondTruncation(t *testing.T) {
+	second := time.Date(2026, 1, 1, 0, 0, 5, 0, time.UTC)
+	checkpoint := second.Add(300 * time.Millisecond) // last line delivered before the crash
+
+	// SinceTime truncates to the start of the second, so the server resends
+	// every line from `second` onward, including ones already delivered.
+	resent := []time.Time{
+		second,
+		second.Add(100 * time.Millisecond),
+		checkpoint,                             // already delivered
+		checkpoint.Add(200 * time.Millisecond), // new
+		second.Add(time.Second),                // new
+	}
+
+	var delivered int
+	for _, ts := range resent {
+		if !isDuplicateResumedLine(ts, checkpoint) {
+			delivered++
+		}
+	}
+
+	if want := 2; delivered != want {
+		t.Errorf("delivered %d lines after resume, want %d (only genuinely new lines)", delivered, want)
+	}
+}
+
+func TestSplitTimestampedLine(t *testing.T) {
+	cases := []struct {
+		name     string
+		line     string
+		wantTS   bool
+		wantLine string
+	}{
+		{
+			name:     "valid timestamp",
+			line:     "2026-01-01T00:00:05.123456789Z hello world\n",
+			wantTS:   true,
+			wantLine: "hello world\n",
+		},
+		{
+			name:     "no space",
+			line:     "no-timestamp-here",
+			wantTS:   false,
+			wantLine: "no-timestamp-here",
+		},
+		{
+			name:     "unparseable prefix",
+			line:     "not-a-timestamp rest of line\n",
+			wantTS:   false,
+			wantLine: "not-a-timestamp rest of line\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ts, content := splitTimestampedLine([]byte(c.line))
+			if ts.IsZero() == c.wantTS {
+				t.Errorf("splitTimestampedLine(%q) ts.IsZero() = %v, want wantTS=%v", c.line, ts.IsZero(), c.wantTS)
+			}
+			if string(content) != c.wantLine {
+				t.Errorf("splitTimestampedLine(%q) content = %q, want %q", c.line, content, c.wantLine)
+			}
+		})
+	}
+}
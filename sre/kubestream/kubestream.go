@@ -3,49 +3,103 @@ package main
 import (
 	"bufio"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/util/homedir"
 )
 
 const (
 	// Configuration constants
-	watchRestartBaseDelay = 1 * time.Second
-	watchRestartMaxDelay  = 32 * time.Second
-	maxWatchRetries       = 5
-	streamStartDelay      = 500 * time.Millisecond
+	defaultResyncPeriod      = 30 * time.Second
+	streamStartDelay         = 500 * time.Millisecond
+	streamRestartBaseDelay   = 1 * time.Second
+	streamRestartMaxDelay    = 30 * time.Second
+	defaultMaxStreamAttempts = 10
 )
 
-// LogOutput provides thread-safe output operations
-type LogOutput struct {
-	mu     sync.Mutex
-	writer io.Writer
+// exponentialBackoff computes the delay for a given retry attempt (1-indexed):
+// base * 2^(attempt-1), capped at max.
+func exponentialBackoff(attempt int, base, max time.Duration) time.Duration {
+	delay := time.Duration(1<<uint(attempt-1)) * base
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// streamErrorKind classifies a log-streaming failure as recoverable (worth
+// retrying the same container) or terminal (the container is gone for good).
+type streamErrorKind int
+
+const (
+	streamErrorRecoverable streamErrorKind = iota
+	streamErrorTerminal
+)
+
+// streamError wraps a log-streaming failure with a recoverable/terminal
+// classification so callers can decide whether to redial GetLogs.
+type streamError struct {
+	kind streamErrorKind
+	err  error
+}
+
+func (e *streamError) Error() string { return e.err.Error() }
+func (e *streamError) Unwrap() error { return e.err }
+
+// Recoverable reports whether the stream should be redialed rather than abandoned.
+func (e *streamError) Recoverable() bool { return e.kind == streamErrorRecoverable }
+
+func recoverableLogError(err error) *streamError {
+	return &streamError{kind: streamErrorRecoverable, err: err}
 }
 
-func NewLogOutput(w io.Writer) *LogOutput {
-	return &LogOutput{writer: w}
+func terminalLogError(err error) *streamError {
+	return &streamError{kind: streamErrorTerminal, err: err}
 }
 
-// WriteLine provides thread-safe output to reduce interleaving
-func (lo *LogOutput) WriteLine(prefix, line string) {
-	lo.mu.Lock()
-	defer lo.mu.Unlock()
-	fmt.Fprintf(lo.writer, "%s%s", prefix, line)
+// classifyStreamError turns a raw error from GetLogs/Stream/ReadBytes into a
+// streamError, distinguishing transient conditions (container still starting,
+// brief pod restart, network blips) from conditions that mean the container
+// is never coming back.
+func classifyStreamError(err error) *streamError {
+	if apierrors.IsNotFound(err) {
+		return recoverableLogError(fmt.Errorf("pod or container not found, may be restarting: %w", err))
+	}
+	if apierrors.IsGone(err) {
+		return recoverableLogError(fmt.Errorf("log stream expired: %w", err))
+	}
+	if strings.Contains(err.Error(), "ContainerCreating") || strings.Contains(err.Error(), "waiting to start") {
+		return recoverableLogError(fmt.Errorf("container still starting: %w", err))
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return recoverableLogError(fmt.Errorf("transient network error: %w", err))
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return recoverableLogError(fmt.Errorf("stream closed unexpectedly: %w", err))
+	}
+	return terminalLogError(err)
 }
 
 // StreamKey generates a unique key for a container stream
@@ -92,7 +146,7 @@ func (sr *StreamRegistry) Remove(key StreamKey) {
 	if info, exists := sr.streams[key]; exists {
 		// Cancel the context to signal the goroutine to stop
 		// Note: Multiple cancel() calls are safe but this may be called
-		// both from external stopPodStreams() and from streamLogs() defer
+		// both from external stopPodStreams() and from streamWithRetry() defer
 		info.cancel()
 		delete(sr.streams, key)
 	}
@@ -138,118 +192,211 @@ func (sr *StreamRegistry) Count() int {
 	return len(sr.streams)
 }
 
-// PodWatcher handles pod lifecycle events
-type PodWatcher struct {
-	clientset   *kubernetes.Clientset
-	registry    *StreamRegistry
-	namespace   string
-	streamDelay time.Duration
-	logOutput   *LogOutput
+// PodFilter narrows which pods and containers are streamed. A nil LabelSelector
+// matches all pods, and a nil regexp for any of the include/exclude fields means
+// that filter is not applied.
+type PodFilter struct {
+	LabelSelector    labels.Selector
+	PodInclude       *regexp.Regexp
+	PodExclude       *regexp.Regexp
+	ContainerInclude *regexp.Regexp
+	ContainerExclude *regexp.Regexp
 }
 
-func NewPodWatcher(clientset *kubernetes.Clientset, registry *StreamRegistry, namespace string, streamDelay time.Duration, logOutput *LogOutput) *PodWatcher {
-	return &PodWatcher{
-		clientset:   clientset,
-		registry:    registry,
-		namespace:   namespace,
-		streamDelay: streamDelay,
-		logOutput:   logOutput,
+// matchesPod reports whether a pod name passes the include/exclude regexes.
+func (f PodFilter) matchesPod(name string) bool {
+	if f.PodInclude != nil && !f.PodInclude.MatchString(name) {
+		return false
+	}
+	if f.PodExclude != nil && f.PodExclude.MatchString(name) {
+		return false
 	}
+	return true
 }
 
-// WatchWithRetry implements exponential backoff for watch failures
-// Backoff progression: 1s, 2s, 4s, 8s, 16s, 32s (capped), then reset to 1s
-func (pw *PodWatcher) WatchWithRetry(ctx context.Context) error {
-	retries := 0
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			err := pw.watch(ctx)
-			if ctx.Err() != nil {
-				return ctx.Err() // Context cancelled, exit gracefully
-			}
+// matchesContainer reports whether a container name passes the include/exclude regexes.
+func (f PodFilter) matchesContainer(name string) bool {
+	if f.ContainerInclude != nil && !f.ContainerInclude.MatchString(name) {
+		return false
+	}
+	if f.ContainerExclude != nil && f.ContainerExclude.MatchString(name) {
+		return false
+	}
+	return true
+}
 
-			retries++
-			if retries > maxWatchRetries {
-				log.Printf("Max watch retries exceeded for namespace %s, resetting backoff", pw.namespace)
-				retries = 1 // Reset to 1 instead of 0 to maintain some delay
-			}
+// selector returns the configured label selector, or labels.Everything() if none was set.
+func (f PodFilter) selector() labels.Selector {
+	if f.LabelSelector == nil {
+		return labels.Everything()
+	}
+	return f.LabelSelector
+}
 
-			// True exponential backoff: base * 2^(retries-1), capped at max
-			delay := time.Duration(1<<uint(retries-1)) * watchRestartBaseDelay
-			if delay > watchRestartMaxDelay {
-				delay = watchRestartMaxDelay
-			}
+// StreamOptions controls which portion of a container's log history is
+// requested when a stream is (re)opened. A nil field leaves the corresponding
+// PodLogOptions field unset, i.e. the Kubernetes API server default applies.
+type StreamOptions struct {
+	SinceSeconds *int64
+	SinceTime    *metav1.Time
+	TailLines    *int64
+}
 
-			log.Printf("Pod watcher error for namespace %s: %v, restarting in %v... (attempt %d)",
-				pw.namespace, err, delay, retries)
+// apply copies the configured options onto opts. SinceTime takes precedence
+// over SinceSeconds, matching the corev1.PodLogOptions validation rule that
+// only one of the two may be set.
+func (o StreamOptions) apply(opts *corev1.PodLogOptions) {
+	switch {
+	case o.SinceTime != nil:
+		opts.SinceTime = o.SinceTime
+	case o.SinceSeconds != nil:
+		opts.SinceSeconds = o.SinceSeconds
+	}
+	if o.TailLines != nil {
+		opts.TailLines = o.TailLines
+	}
+}
 
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case <-time.After(delay):
-				continue
-			}
-		}
+// PodWatcher handles pod lifecycle events
+type PodWatcher struct {
+	clientset         *kubernetes.Clientset
+	registry          *StreamRegistry
+	namespace         string
+	streamDelay       time.Duration
+	sink              Sink
+	filter            PodFilter
+	resyncPeriod      time.Duration
+	maxStreamAttempts int
+	streamOptions     StreamOptions
+	checkpointer      *Checkpointer
+}
+
+// PodWatcherOption configures optional PodWatcher behavior.
+type PodWatcherOption func(*PodWatcher)
+
+// WithResyncPeriod sets how often the shared informer resyncs its local
+// cache by replaying all known pods through the event handler.
+func WithResyncPeriod(period time.Duration) PodWatcherOption {
+	return func(pw *PodWatcher) {
+		pw.resyncPeriod = period
 	}
 }
 
-func (pw *PodWatcher) watch(ctx context.Context) error {
-	// First, start streams for existing pods
-	pods, err := pw.clientset.CoreV1().Pods(pw.namespace).List(ctx, metav1.ListOptions{})
-	if err != nil {
-		return fmt.Errorf("error listing existing pods in namespace %s: %v", pw.namespace, err)
+// WithMaxStreamAttempts sets how many consecutive recoverable log-stream
+// failures a single container may have before it is abandoned.
+func WithMaxStreamAttempts(attempts int) PodWatcherOption {
+	return func(pw *PodWatcher) {
+		pw.maxStreamAttempts = attempts
 	}
+}
 
-	for _, pod := range pods.Items {
-		if isPodReady(&pod) {
-			pw.startPodStreams(ctx, &pod)
-		}
+// WithStreamOptions sets the since/since-time/tail options applied to newly
+// opened log streams that have no checkpoint to resume from.
+func WithStreamOptions(opts StreamOptions) PodWatcherOption {
+	return func(pw *PodWatcher) {
+		pw.streamOptions = opts
+	}
+}
+
+// WithCheckpointer enables persistent checkpointing of the last-seen log
+// timestamp per container, so streams resume from SinceTime after a restart.
+// A nil checkpointer (the default) disables checkpointing.
+func WithCheckpointer(c *Checkpointer) PodWatcherOption {
+	return func(pw *PodWatcher) {
+		pw.checkpointer = c
 	}
+}
 
-	// Set up watch for pod events
-	watcher, err := pw.clientset.CoreV1().Pods(pw.namespace).Watch(ctx, metav1.ListOptions{})
+func NewPodWatcher(clientset *kubernetes.Clientset, registry *StreamRegistry, namespace string, streamDelay time.Duration, sink Sink, filter PodFilter, opts ...PodWatcherOption) *PodWatcher {
+	pw := &PodWatcher{
+		clientset:         clientset,
+		registry:          registry,
+		namespace:         namespace,
+		streamDelay:       streamDelay,
+		sink:              sink,
+		filter:            filter,
+		resyncPeriod:      defaultResyncPeriod,
+		maxStreamAttempts: defaultMaxStreamAttempts,
+	}
+	for _, opt := range opts {
+		opt(pw)
+	}
+	return pw
+}
+
+// Run starts a namespace-scoped SharedInformerFactory over pods and drives
+// startPodStreams/stopPodStreams from its event handler. It blocks until ctx
+// is cancelled, then drains the informer's goroutines before returning, so
+// that callers waiting on a sync.WaitGroup see all informer activity settled.
+func (pw *PodWatcher) Run(ctx context.Context) error {
+	factory := informers.NewSharedInformerFactoryWithOptions(pw.clientset, pw.resyncPeriod,
+		informers.WithNamespace(pw.namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = pw.filter.selector().String()
+		}),
+	)
+
+	podInformer := factory.Core().V1().Pods().Informer()
+	_, err := podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			pw.handlePodEvent(ctx, obj)
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			pw.handlePodEvent(ctx, newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			pw.handlePodDelete(obj)
+		},
+	})
 	if err != nil {
-		return fmt.Errorf("error setting up pod watcher for namespace %s: %v", pw.namespace, err)
+		return fmt.Errorf("error registering pod event handler for namespace %s: %v", pw.namespace, err)
 	}
-	defer watcher.Stop()
 
-	log.Printf("Watching pods in namespace: %s", pw.namespace)
+	factory.Start(ctx.Done())
+	defer factory.Shutdown()
 
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case event, ok := <-watcher.ResultChan():
-			if !ok {
-				return fmt.Errorf("pod watcher channel closed for namespace %s", pw.namespace)
-			}
+	if !cache.WaitForCacheSync(ctx.Done(), podInformer.HasSynced) {
+		return fmt.Errorf("failed to sync pod informer cache for namespace %s", pw.namespace)
+	}
 
-			pod, ok := event.Object.(*corev1.Pod)
-			if !ok {
-				log.Printf("Unexpected event object type in namespace %s", pw.namespace)
-				continue
-			}
+	log.Printf("Watching pods in namespace: %s (informer, resync=%v)", pw.namespace, pw.resyncPeriod)
 
-			switch event.Type {
-			case watch.Added, watch.Modified:
-				if isPodReady(pod) {
-					pw.startPodStreams(ctx, pod)
-				} else {
-					pw.stopPodStreams(pod)
-				}
-			case watch.Deleted:
-				pw.stopPodStreams(pod)
-			case watch.Error:
-				if statusErr, ok := event.Object.(*metav1.Status); ok {
-					return fmt.Errorf("watch error in namespace %s: %v", pw.namespace, statusErr.Message)
-				}
-				return fmt.Errorf("unknown watch error in namespace %s", pw.namespace)
-			}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// handlePodEvent reacts to an informer Add/Update event for a pod.
+func (pw *PodWatcher) handlePodEvent(ctx context.Context, obj interface{}) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		log.Printf("Unexpected event object type in namespace %s", pw.namespace)
+		return
+	}
+	if isPodReady(pod) {
+		pw.startPodStreams(ctx, pod)
+	} else {
+		pw.stopPodStreams(pod)
+	}
+}
+
+// handlePodDelete reacts to an informer Delete event, unwrapping the
+// cache.DeletedFinalStateUnknown tombstone the informer delivers when it
+// misses the actual delete event.
+func (pw *PodWatcher) handlePodDelete(obj interface{}) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			log.Printf("Unexpected delete event object type in namespace %s", pw.namespace)
+			return
+		}
+		pod, ok = tombstone.Obj.(*corev1.Pod)
+		if !ok {
+			log.Printf("Unexpected tombstone object type in namespace %s", pw.namespace)
+			return
 		}
 	}
+	pw.stopPodStreams(pod)
 }
 
 // ContainerInfo represents a container that needs log streaming
@@ -258,12 +405,16 @@ type ContainerInfo struct {
 	Type string // "container" or "init"
 }
 
-// getContainers extracts all containers from a pod (DRY helper)
+// getContainers extracts all containers from a pod (DRY helper), honoring the
+// watcher's container include/exclude filter.
 func (pw *PodWatcher) getContainers(pod *corev1.Pod) []ContainerInfo {
 	var containers []ContainerInfo
 
 	// Add regular containers
 	for _, container := range pod.Spec.Containers {
+		if !pw.filter.matchesContainer(container.Name) {
+			continue
+		}
 		containers = append(containers, ContainerInfo{
 			Name: container.Name,
 			Type: "container",
@@ -272,6 +423,9 @@ func (pw *PodWatcher) getContainers(pod *corev1.Pod) []ContainerInfo {
 
 	// Add init containers
 	for _, initContainer := range pod.Spec.InitContainers {
+		if !pw.filter.matchesContainer(initContainer.Name) {
+			continue
+		}
 		containers = append(containers, ContainerInfo{
 			Name: initContainer.Name,
 			Type: "init",
@@ -282,6 +436,9 @@ func (pw *PodWatcher) getContainers(pod *corev1.Pod) []ContainerInfo {
 }
 
 func (pw *PodWatcher) startPodStreams(ctx context.Context, pod *corev1.Pod) {
+	if !pw.filter.matchesPod(pod.Name) {
+		return
+	}
 	containers := pw.getContainers(pod)
 
 	for _, container := range containers {
@@ -289,7 +446,7 @@ func (pw *PodWatcher) startPodStreams(ctx context.Context, pod *corev1.Pod) {
 		if !pw.registry.Exists(key) {
 			streamCtx, cancel := context.WithCancel(ctx)
 			pw.registry.Add(key, cancel)
-			go pw.streamLogs(streamCtx, pod.Namespace, pod.Name, container.Name, container.Type)
+			go pw.streamWithRetry(streamCtx, pod.Namespace, pod.Name, container.Name, container.Type)
 		}
 	}
 }
@@ -303,32 +460,83 @@ func (pw *PodWatcher) stopPodStreams(pod *corev1.Pod) {
 	}
 }
 
-func (pw *PodWatcher) streamLogs(ctx context.Context, namespace, podName, containerName, containerType string) {
+// streamWithRetry owns the lifecycle of a single container's log stream: it
+// calls streamLogs repeatedly, redialing with exponential backoff on
+// recoverable errors (container starting, brief pod restart, transient
+// network errors) and giving up on terminal errors or on a recoverable failure
+// that follows maxStreamAttempts consecutive redials.
+func (pw *PodWatcher) streamWithRetry(ctx context.Context, namespace, podName, containerName, containerType string) {
 	key := NewStreamKey(namespace, podName, containerName)
 
-	// Retrieve the cancel function for this stream to ensure proper cleanup
 	var cancel context.CancelFunc
 	if info, exists := pw.registry.Get(key); exists {
 		cancel = info.cancel
 	}
 
-	// Ensure proper cleanup regardless of how this goroutine exits
 	defer func() {
-		// Call cancel to signal completion and free resources
 		if cancel != nil {
 			cancel()
 		}
-		// Remove from registry without additional cancel (already called above)
 		pw.registry.RemoveWithoutCancel(key)
-		log.Printf("Stopped log stream for %s (%s)", key, containerType)
 	}()
 
+	attempt := 0
+	for {
+		delivered, err := pw.streamLogs(ctx, namespace, podName, containerName, containerType)
+		if delivered {
+			// The stream read at least one line before failing, so whatever
+			// was wrong has cleared; don't let an old, unrelated failure
+			// count against this fresh run.
+			attempt = 0
+		}
+		if ctx.Err() != nil {
+			log.Printf("stream state=stopped key=%s type=%s reason=context-cancelled", key, containerType)
+			return
+		}
+		if err == nil {
+			log.Printf("stream state=stopped key=%s type=%s reason=eof", key, containerType)
+			return
+		}
+
+		var sErr *streamError
+		if !errors.As(err, &sErr) || !sErr.Recoverable() {
+			log.Printf("stream state=stopped key=%s type=%s reason=terminal err=%v", key, containerType, err)
+			return
+		}
+
+		attempt++
+		if attempt > pw.maxStreamAttempts {
+			log.Printf("stream state=stopped key=%s type=%s reason=max-attempts attempts=%d", key, containerType, attempt)
+			return
+		}
+
+		delay := exponentialBackoff(attempt, streamRestartBaseDelay, streamRestartMaxDelay)
+		log.Printf("stream state=retrying key=%s type=%s attempt=%d delay=%v err=%v", key, containerType, attempt, delay, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// streamLogs opens one GetLogs stream for a container and copies lines to the
+// configured Sink until the stream ends or fails. It returns nil when the
+// stream ends because the context was cancelled or the underlying stream hit
+// EOF, and a *streamError otherwise so the caller can decide whether to
+// retry. The returned bool reports whether at least one line was delivered
+// to the sink, so callers can distinguish a stream that failed immediately
+// from one that ran successfully for a while before failing.
+func (pw *PodWatcher) streamLogs(ctx context.Context, namespace, podName, containerName, containerType string) (bool, error) {
+	key := NewStreamKey(namespace, podName, containerName)
+
 	// Use a context-aware delay instead of hard-coded sleep
 	// Skip delay if configured to 0 for immediate streaming
 	if pw.streamDelay > 0 {
 		select {
 		case <-ctx.Done():
-			return
+			return false, nil
 		case <-time.After(pw.streamDelay):
 			// Continue to stream setup
 		}
@@ -341,37 +549,64 @@ func (pw *PodWatcher) streamLogs(ctx context.Context, namespace, podName, contai
 		Timestamps: true,
 	}
 
+	// resumeAfter is the nanosecond-precision checkpoint. SinceTime only
+	// round-trips through the API server with second precision
+	// (metav1.Time.MarshalQueryParameter formats with time.RFC3339), so it
+	// alone would re-deliver a whole second's worth of already-seen lines on
+	// every restart; resumeAfter lets the read loop below drop those
+	// duplicates client-side.
+	var resumeAfter time.Time
+	if pw.checkpointer != nil {
+		if ts, ok := pw.checkpointer.Load(key); ok {
+			checkpointTime := metav1.NewTime(ts)
+			podLogOpts.SinceTime = &checkpointTime
+			resumeAfter = ts
+		}
+	}
+	if podLogOpts.SinceTime == nil {
+		pw.streamOptions.apply(&podLogOpts)
+	}
+
 	req := pw.clientset.CoreV1().Pods(namespace).GetLogs(podName, &podLogOpts)
 	stream, err := req.Stream(ctx)
 	if err != nil {
-		log.Printf("Error opening stream for %s (%s): %v", key, containerType, err)
-		return
+		return false, classifyStreamError(err)
 	}
 	defer stream.Close()
 
 	reader := bufio.NewReader(stream)
-	// Use clean prefix - Kubernetes timestamps are more accurate than local receive time
-	prefix := fmt.Sprintf("[%s/%s/%s:%s] ", namespace, podName, containerName, containerType)
 
 	log.Printf("Started log stream for %s (%s)", key, containerType)
 
+	delivered := false
 	for {
 		select {
 		case <-ctx.Done():
-			// Log message moved to defer block for consistency
-			return
+			return delivered, nil
 		default:
 			line, err := reader.ReadBytes('\n')
 			if err != nil {
-				if err == io.EOF {
-					log.Printf("Log stream ended for %s (%s)", key, containerType)
-				} else {
-					log.Printf("Error reading log stream for %s (%s): %v", key, containerType, err)
+				if err == io.EOF || ctx.Err() != nil {
+					return delivered, nil
 				}
-				return
+				return delivered, classifyStreamError(err)
+			}
+			ts, content := splitTimestampedLine(line)
+			if isDuplicateResumedLine(ts, resumeAfter) {
+				continue
+			}
+			meta := LineMeta{
+				Namespace: namespace,
+				Pod:       podName,
+				Container: containerName,
+				Type:      containerType,
+				Timestamp: ts,
+			}
+			pw.sink.WriteLine(meta, content)
+			delivered = true
+			if pw.checkpointer != nil {
+				pw.checkpointer.Update(key, ts)
 			}
-			// Use thread-safe output to reduce interleaving
-			pw.logOutput.WriteLine(prefix, string(line))
 		}
 	}
 }
@@ -386,8 +621,76 @@ func main() {
 
 	namespacesStr := flag.String("namespaces", "", "Comma-separated list of namespaces to watch. If empty, all namespaces will be watched.")
 	streamDelay := flag.Duration("stream-delay", streamStartDelay, "Delay before starting log streams for new containers (helps with container startup)")
+	labelSelectorStr := flag.String("label-selector", "", "Kubernetes label selector used to narrow down which pods are watched (e.g. 'app=myapp')")
+	podIncludeStr := flag.String("pod-include", "", "Regex; only pods whose name matches are streamed")
+	podExcludeStr := flag.String("pod-exclude", "", "Regex; pods whose name matches are skipped")
+	containerIncludeStr := flag.String("container-include", "", "Regex; only containers whose name matches are streamed")
+	containerExcludeStr := flag.String("container-exclude", "", "Regex; containers whose name matches are skipped (e.g. sidecars)")
+	resyncPeriod := flag.Duration("resync-period", defaultResyncPeriod, "How often the pod informer resyncs its local cache")
+	maxStreamAttempts := flag.Int("max-stream-attempts", defaultMaxStreamAttempts, "Max consecutive recoverable log-stream failures before a container's stream is abandoned")
+	output := flag.String("output", "text", "Output sink: text|json|files|loki")
+	filesDir := flag.String("files-dir", "logs", "Directory to write to when --output=files")
+	filesMaxBytes := flag.Int64("files-max-bytes", defaultFilesSinkMaxBytes, "Rotate a container's log file before a write would take it past this many bytes when --output=files")
+	lokiURL := flag.String("loki-url", "", "Loki /loki/api/v1/push endpoint when --output=loki")
+	lokiBatchSize := flag.Int("loki-batch-size", defaultLokiBatchSize, "Number of lines to batch per Loki push when --output=loki")
+	lokiFlushInterval := flag.Duration("loki-flush-interval", defaultLokiFlushInterval, "Max time to hold a batch before pushing to Loki when --output=loki")
+	since := flag.Duration("since", 0, "Only show logs newer than this duration (mutually exclusive with --since-time)")
+	sinceTimeStr := flag.String("since-time", "", "Only show logs newer than this RFC3339 timestamp (mutually exclusive with --since)")
+	tailLines := flag.Int64("tail", 0, "Number of lines to show from the end of the log, per container (0 means unset)")
+	checkpointDir := flag.String("checkpoint-dir", "", "Directory to persist per-container checkpoints in, so streams resume after a restart instead of duplicating or losing lines")
+	checkpointInterval := flag.Duration("checkpoint-interval", defaultCheckpointInterval, "How often to flush checkpoints to --checkpoint-dir")
 	flag.Parse()
 
+	filter := PodFilter{}
+	if *labelSelectorStr != "" {
+		sel, err := labels.Parse(*labelSelectorStr)
+		if err != nil {
+			log.Fatalf("Error parsing label selector %q: %v", *labelSelectorStr, err)
+		}
+		filter.LabelSelector = sel
+	}
+	filter.PodInclude = mustCompileOptionalRegexp("pod-include", *podIncludeStr)
+	filter.PodExclude = mustCompileOptionalRegexp("pod-exclude", *podExcludeStr)
+	filter.ContainerInclude = mustCompileOptionalRegexp("container-include", *containerIncludeStr)
+	filter.ContainerExclude = mustCompileOptionalRegexp("container-exclude", *containerExcludeStr)
+
+	if *since > 0 && *sinceTimeStr != "" {
+		log.Fatalf("--since and --since-time are mutually exclusive")
+	}
+	var streamOpts StreamOptions
+	if *since > 0 {
+		sinceSeconds := int64(since.Seconds())
+		streamOpts.SinceSeconds = &sinceSeconds
+	}
+	if *sinceTimeStr != "" {
+		parsed, err := time.Parse(time.RFC3339, *sinceTimeStr)
+		if err != nil {
+			log.Fatalf("Error parsing --since-time %q: %v", *sinceTimeStr, err)
+		}
+		sinceTime := metav1.NewTime(parsed)
+		streamOpts.SinceTime = &sinceTime
+	}
+	if *tailLines > 0 {
+		streamOpts.TailLines = tailLines
+	}
+
+	var checkpointer *Checkpointer
+	if *checkpointDir != "" {
+		checkpointer = NewCheckpointer(*checkpointDir)
+	}
+
+	sink, err := newSink(*output, sinkConfig{
+		filesDir:          *filesDir,
+		filesMaxBytes:     *filesMaxBytes,
+		lokiURL:           *lokiURL,
+		lokiBatchSize:     *lokiBatchSize,
+		lokiFlushInterval: *lokiFlushInterval,
+	})
+	if err != nil {
+		log.Fatalf("Error configuring output sink: %v", err)
+	}
+	defer sink.Close()
+
 	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
 	if err != nil {
 		log.Fatalf("Error building kubeconfig: %v", err)
@@ -425,8 +728,12 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
+	if checkpointer != nil {
+		go checkpointer.Run(ctx, *checkpointInterval)
+		defer checkpointer.Flush()
+	}
+
 	registry := NewStreamRegistry()
-	logOutput := NewLogOutput(os.Stdout)
 	var wg sync.WaitGroup
 
 	// Start pod watchers for each namespace
@@ -434,8 +741,10 @@ func main() {
 		wg.Add(1)
 		go func(namespace string) {
 			defer wg.Done()
-			watcher := NewPodWatcher(clientset, registry, namespace, *streamDelay, logOutput)
-			if err := watcher.WatchWithRetry(ctx); err != nil {
+			watcher := NewPodWatcher(clientset, registry, namespace, *streamDelay, sink, filter,
+				WithResyncPeriod(*resyncPeriod), WithMaxStreamAttempts(*maxStreamAttempts),
+				WithStreamOptions(streamOpts), WithCheckpointer(checkpointer))
+			if err := watcher.Run(ctx); err != nil {
 				if ctx.Err() == nil {
 					log.Printf("Pod watcher failed for namespace %s: %v", namespace, err)
 				}
@@ -474,6 +783,20 @@ func main() {
 	log.Println("All streams stopped, exiting.")
 }
 
+// mustCompileOptionalRegexp compiles pattern if non-empty, exiting the process
+// with a descriptive error if it is not a valid regex. An empty pattern yields
+// a nil *regexp.Regexp, meaning "no filter".
+func mustCompileOptionalRegexp(flagName, pattern string) *regexp.Regexp {
+	if pattern == "" {
+		return nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		log.Fatalf("Error compiling --%s regex %q: %v", flagName, pattern, err)
+	}
+	return re
+}
+
 func isPodReady(pod *corev1.Pod) bool {
 	// Check if pod is in Running phase and all containers are ready
 	if pod.Status.Phase != corev1.PodRunning {
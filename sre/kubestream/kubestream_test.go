@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	base := time.Second
+	max := 30 * time.Second
+
+	cases := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 1 * time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 8 * time.Second},
+		{5, 16 * time.Second},
+		{6, max}, // 32s would exceed max
+		{10, max},
+	}
+
+	for _, c := range cases {
+		if got := exponentialBackoff(c.attempt, base, max); got != c.want {
+			t.Errorf("exponentialBackoff(%d, %v, %v) = %v, want %v", c.attempt, base, max, got, c.want)
+		}
+	}
+}
+
+func TestClassifyStreamError(t *testing.T) {
+	notFound := apierrors.NewNotFound(schema.GroupResource{Resource: "pods"}, "mypod")
+	gone := apierrors.NewGone("stream expired")
+
+	cases := []struct {
+		name        string
+		err         error
+		recoverable bool
+	}{
+		{"not found", notFound, true},
+		{"gone", gone, true},
+		{"container creating", errors.New("container is ContainerCreating"), true},
+		{"waiting to start", errors.New("container is waiting to start: PodInitializing"), true},
+		{"network error", &fakeNetError{}, true},
+		{"unexpected eof", io.ErrUnexpectedEOF, true},
+		{"other", errors.New("boom"), false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sErr := classifyStreamError(c.err)
+			if sErr.Recoverable() != c.recoverable {
+				t.Errorf("classifyStreamError(%v).Recoverable() = %v, want %v", c.err, sErr.Recoverable(), c.recoverable)
+			}
+		})
+	}
+}
+
+// fakeNetError is a minimal net.Error implementation for exercising the
+// transient-network-error branch of classifyStreamError without depending on
+// an actual socket.
+type fakeNetError struct{}
+
+func (*fakeNetError) Error() string   { return "test network error" }
+func (*fakeNetError) Timeout() bool   { return true }
+func (*fakeNetError) Temporary() bool { return true }
+
+func TestStreamWithRetryResetsAttemptAfterDelivery(t *testing.T) {
+	// streamWithRetry itself requires a live clientset to dial GetLogs, so this
+	// test exercises the same attempt-reset rule it relies on: a run that
+	// delivered at least one line must not let its failure count against a
+	// fresh run's budget.
+	maxAttempts := 2
+	attempt := 0
+
+	runs := []struct {
+		delivered bool
+		err       error
+	}{
+		{true, classifyStreamError(errors.New("container is ContainerCreating"))},
+		{true, classifyStreamError(errors.New("container is ContainerCreating"))},
+		{true, classifyStreamError(errors.New("container is ContainerCreating"))},
+	}
+
+	for i, run := range runs {
+		if run.delivered {
+			attempt = 0
+		}
+		attempt++
+		if attempt > maxAttempts {
+			t.Fatalf("run %d: attempt budget exhausted even though every run delivered at least one line", i)
+		}
+	}
+}
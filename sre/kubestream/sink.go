@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// LineMeta carries the structured identity of a single log line: which
+// namespace/pod/container it came from, whether that container is a regular
+// or init container, and the Kubernetes-assigned timestamp parsed from the
+// RFC3339 prefix the API server adds when PodLogOptions.Timestamps is set.
+// Timestamp is the zero time if the line had no parseable timestamp prefix.
+type LineMeta struct {
+	Namespace string
+	Pod       string
+	Container string
+	Type      string
+	Timestamp time.Time
+}
+
+// Sink receives parsed log lines for delivery to a destination (stdout, a
+// file tree, Loki, ...). Implementations must be safe for concurrent use,
+// since every container stream writes to the same Sink from its own goroutine.
+type Sink interface {
+	// WriteLine delivers one log line. line is the raw log content with the
+	// leading Kubernetes RFC3339 timestamp already stripped into meta.Timestamp.
+	WriteLine(meta LineMeta, line []byte)
+	// Close flushes and releases any resources held by the sink.
+	Close() error
+}
+
+// splitTimestampedLine splits a line emitted with PodLogOptions.Timestamps
+// into its leading RFC3339Nano timestamp and the remaining content. If the
+// line has no parseable timestamp prefix, it returns the zero time and the
+// line unchanged.
+func splitTimestampedLine(line []byte) (time.Time, []byte) {
+	idx := bytes.IndexByte(line, ' ')
+	if idx < 0 {
+		return time.Time{}, line
+	}
+	ts, err := time.Parse(time.RFC3339Nano, string(line[:idx]))
+	if err != nil {
+		return time.Time{}, line
+	}
+	return ts, line[idx+1:]
+}
+
+// TextSink writes human-readable, prefixed lines to a single writer (stdout
+// by default). It is the default --output=text sink and the direct
+// replacement for the tool's original plain-text behavior.
+type TextSink struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+func NewTextSink(w io.Writer) *TextSink {
+	return &TextSink{writer: w}
+}
+
+func (s *TextSink) WriteLine(meta LineMeta, line []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.writer, "[%s/%s/%s:%s] %s %s", meta.Namespace, meta.Pod, meta.Container, meta.Type,
+		meta.Timestamp.Format(time.RFC3339Nano), line)
+}
+
+func (s *TextSink) Close() error {
+	return nil
+}
+
+// sinkConfig bundles the CLI flags needed to construct any of the Sink
+// implementations that take configuration beyond an output writer.
+type sinkConfig struct {
+	filesDir          string
+	filesMaxBytes     int64
+	lokiURL           string
+	lokiBatchSize     int
+	lokiFlushInterval time.Duration
+}
+
+// newSink builds the Sink selected by --output.
+func newSink(output string, cfg sinkConfig) (Sink, error) {
+	switch output {
+	case "", "text":
+		return NewTextSink(os.Stdout), nil
+	case "json":
+		return NewJSONSink(os.Stdout), nil
+	case "files":
+		return NewFilesSink(cfg.filesDir, cfg.filesMaxBytes), nil
+	case "loki":
+		if cfg.lokiURL == "" {
+			return nil, fmt.Errorf("--loki-url is required when --output=loki")
+		}
+		return NewLokiSink(cfg.lokiURL, cfg.lokiBatchSize, cfg.lokiFlushInterval), nil
+	default:
+		return nil, fmt.Errorf("unknown output sink %q (want text, json, files, or loki)", output)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// defaultFilesSinkMaxBytes is the rotation threshold used when FilesSink is
+// selected without an explicit --files-max-bytes override.
+const defaultFilesSinkMaxBytes = 100 * 1024 * 1024
+
+// FilesSink writes one log file per container under
+// <dir>/<namespace>/<pod>/<container>.log, rotating and gzip-compressing the
+// current segment before a write would take it past maxBytes. Selected with --output=files.
+type FilesSink struct {
+	mu       sync.Mutex
+	dir      string
+	maxBytes int64
+	files    map[StreamKey]*rotatingFile
+}
+
+func NewFilesSink(dir string, maxBytes int64) *FilesSink {
+	return &FilesSink{
+		dir:      dir,
+		maxBytes: maxBytes,
+		files:    make(map[StreamKey]*rotatingFile),
+	}
+}
+
+func (s *FilesSink) WriteLine(meta LineMeta, line []byte) {
+	key := NewStreamKey(meta.Namespace, meta.Pod, meta.Container)
+
+	s.mu.Lock()
+	rf, exists := s.files[key]
+	if !exists {
+		path := filepath.Join(s.dir, meta.Namespace, meta.Pod, meta.Container+".log")
+		rf = newRotatingFile(path, s.maxBytes)
+		s.files[key] = rf
+	}
+	s.mu.Unlock()
+
+	if err := rf.write(line); err != nil {
+		log.Printf("Error writing log file for %s: %v", key, err)
+	}
+}
+
+func (s *FilesSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var firstErr error
+	for key, rf := range s.files {
+		if err := rf.close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("closing log file for %s: %w", key, err)
+		}
+	}
+	return firstErr
+}
+
+// rotatingFile is a single container's append-only log file, rotated to a
+// gzip-compressed segment before a write would take it past maxBytes (0
+// disables rotation). A single line larger than maxBytes is still written
+// whole to a fresh segment.
+type rotatingFile struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	file     *os.File
+	size     int64
+}
+
+func newRotatingFile(path string, maxBytes int64) *rotatingFile {
+	return &rotatingFile{path: path, maxBytes: maxBytes}
+}
+
+func (rf *rotatingFile) write(line []byte) error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.file == nil {
+		if err := rf.open(); err != nil {
+			return err
+		}
+	}
+
+	if rf.maxBytes > 0 && rf.size > 0 && rf.size+int64(len(line)) > rf.maxBytes {
+		if err := rf.rotate(); err != nil {
+			return fmt.Errorf("rotating %s: %w", rf.path, err)
+		}
+	}
+
+	n, err := rf.file.Write(line)
+	rf.size += int64(n)
+	return err
+}
+
+func (rf *rotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(rf.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	rf.file = f
+	rf.size = info.Size()
+	return nil
+}
+
+// rotate closes the current file, gzips it alongside the original path with
+// a timestamp suffix, removes the uncompressed original, and opens a fresh file.
+func (rf *rotatingFile) rotate() error {
+	if rf.file != nil {
+		if err := rf.file.Close(); err != nil {
+			return err
+		}
+		rf.file = nil
+	}
+
+	rotatedPath := fmt.Sprintf("%s.%d.gz", rf.path, time.Now().UnixNano())
+	if err := gzipFile(rf.path, rotatedPath); err != nil {
+		return err
+	}
+	if err := os.Remove(rf.path); err != nil {
+		return err
+	}
+
+	rf.size = 0
+	return rf.open()
+}
+
+func (rf *rotatingFile) close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.file == nil {
+		return nil
+	}
+	err := rf.file.Close()
+	rf.file = nil
+	return err
+}
+
+func gzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	gw := gzip.NewWriter(out)
+	if _, err := io.Copy(gw, in); err != nil {
+		gw.Close()
+		return err
+	}
+	return gw.Close()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jsonLine is the on-the-wire shape written by JSONSink, one object per line.
+type jsonLine struct {
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+	Type      string `json:"type"`
+	Timestamp string `json:"timestamp,omitempty"`
+	Line      string `json:"line"`
+}
+
+// JSONSink writes one JSON object per log line, for downstream ingestion by
+// log-shipping agents that expect structured input. Selected with --output=json.
+type JSONSink struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{writer: w}
+}
+
+func (s *JSONSink) WriteLine(meta LineMeta, line []byte) {
+	entry := jsonLine{
+		Namespace: meta.Namespace,
+		Pod:       meta.Pod,
+		Container: meta.Container,
+		Type:      meta.Type,
+		Line:      strings.TrimRight(string(line), "\n"),
+	}
+	if !meta.Timestamp.IsZero() {
+		entry.Timestamp = meta.Timestamp.Format(time.RFC3339Nano)
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("Error marshaling JSON log line for %s/%s/%s: %v", meta.Namespace, meta.Pod, meta.Container, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writer.Write(data)
+	s.writer.Write([]byte("\n"))
+}
+
+func (s *JSONSink) Close() error {
+	return nil
+}
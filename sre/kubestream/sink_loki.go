@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultLokiBatchSize     = 100
+	defaultLokiFlushInterval = 5 * time.Second
+	lokiRequestTimeout       = 10 * time.Second
+)
+
+// lokiEntry is one pending log line waiting to be flushed to Loki.
+type lokiEntry struct {
+	meta LineMeta
+	line string
+}
+
+// LokiSink batches log lines and POSTs them to a Loki /loki/api/v1/push
+// endpoint, grouped into streams labeled by namespace/pod/container/type.
+// Flushes happen when the batch reaches batchSize or flushInterval elapses,
+// whichever comes first. Selected with --output=loki.
+type LokiSink struct {
+	url           string
+	client        *http.Client
+	batchSize     int
+	flushInterval time.Duration
+
+	mu      sync.Mutex
+	pending []lokiEntry
+	timer   *time.Timer
+}
+
+func NewLokiSink(url string, batchSize int, flushInterval time.Duration) *LokiSink {
+	s := &LokiSink{
+		url:           url,
+		client:        &http.Client{Timeout: lokiRequestTimeout},
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+	}
+	s.timer = time.AfterFunc(flushInterval, s.flushAndLog)
+	return s
+}
+
+func (s *LokiSink) flushAndLog() {
+	if err := s.Flush(); err != nil {
+		log.Printf("Error pushing log batch to Loki: %v", err)
+	}
+	s.mu.Lock()
+	s.timer.Reset(s.flushInterval)
+	s.mu.Unlock()
+}
+
+func (s *LokiSink) WriteLine(meta LineMeta, line []byte) {
+	s.mu.Lock()
+	s.pending = append(s.pending, lokiEntry{meta: meta, line: strings.TrimRight(string(line), "\n")})
+	full := len(s.pending) >= s.batchSize
+	s.mu.Unlock()
+
+	if full {
+		if err := s.Flush(); err != nil {
+			log.Printf("Error pushing log batch to Loki: %v", err)
+		}
+	}
+}
+
+// Flush POSTs any pending lines to Loki immediately, grouped by label set.
+func (s *LokiSink) Flush() error {
+	s.mu.Lock()
+	entries := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+
+	if len(entries) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(buildLokiPushRequest(entries))
+	if err != nil {
+		return fmt.Errorf("marshaling Loki push request: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("building Loki push request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("pushing to Loki: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Loki push returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *LokiSink) Close() error {
+	s.timer.Stop()
+	return s.Flush()
+}
+
+type lokiPushRequest struct {
+	Streams []lokiStream `json:"streams"`
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// buildLokiPushRequest groups entries into one Loki stream per distinct
+// namespace/pod/container/type label set, preserving first-seen order.
+func buildLokiPushRequest(entries []lokiEntry) lokiPushRequest {
+	streamsByKey := make(map[StreamKey]*lokiStream)
+	var order []StreamKey
+
+	for _, e := range entries {
+		key := NewStreamKey(e.meta.Namespace, e.meta.Pod, e.meta.Container)
+		stream, exists := streamsByKey[key]
+		if !exists {
+			stream = &lokiStream{
+				Stream: map[string]string{
+					"namespace": e.meta.Namespace,
+					"pod":       e.meta.Pod,
+					"container": e.meta.Container,
+					"type":      e.meta.Type,
+				},
+			}
+			streamsByKey[key] = stream
+			order = append(order, key)
+		}
+
+		ts := e.meta.Timestamp
+		if ts.IsZero() {
+			ts = time.Now()
+		}
+		stream.Values = append(stream.Values, [2]string{fmt.Sprintf("%d", ts.UnixNano()), e.line})
+	}
+
+	req := lokiPushRequest{}
+	for _, key := range order {
+		req.Streams = append(req.Streams, *streamsByKey[key])
+	}
+	return req
+}
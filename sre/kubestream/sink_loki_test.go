@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestBuildLokiPushRequestGroupsByStreamKey(t *testing.T) {
+	t1 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Second)
+
+	entries := []lokiEntry{
+		{meta: LineMeta{Namespace: "default", Pod: "a", Container: "app", Type: "container", Timestamp: t1}, line: "first"},
+		{meta: LineMeta{Namespace: "default", Pod: "a", Container: "app", Type: "container", Timestamp: t2}, line: "second"},
+		{meta: LineMeta{Namespace: "default", Pod: "b", Container: "app", Type: "container", Timestamp: t1}, line: "third"},
+	}
+
+	req := buildLokiPushRequest(entries)
+
+	if len(req.Streams) != 2 {
+		t.Fatalf("got %d streams, want 2", len(req.Streams))
+	}
+
+	first := req.Streams[0]
+	if first.Stream["pod"] != "a" || first.Stream["namespace"] != "default" || first.Stream["container"] != "app" || first.Stream["type"] != "container" {
+		t.Errorf("unexpected labels for first stream: %#v", first.Stream)
+	}
+	if len(first.Values) != 2 {
+		t.Fatalf("first stream has %d values, want 2", len(first.Values))
+	}
+	if want := fmt.Sprintf("%d", t1.UnixNano()); first.Values[0][0] != want {
+		t.Errorf("first value timestamp = %s, want %s", first.Values[0][0], want)
+	}
+	if first.Values[0][1] != "first" || first.Values[1][1] != "second" {
+		t.Errorf("unexpected values for first stream: %#v", first.Values)
+	}
+
+	second := req.Streams[1]
+	if second.Stream["pod"] != "b" {
+		t.Errorf("second stream pod = %s, want b", second.Stream["pod"])
+	}
+	if len(second.Values) != 1 || second.Values[0][1] != "third" {
+		t.Errorf("unexpected values for second stream: %#v", second.Values)
+	}
+}
+
+func TestBuildLokiPushRequestFillsMissingTimestamp(t *testing.T) {
+	entries := []lokiEntry{
+		{meta: LineMeta{Namespace: "default", Pod: "a", Container: "app", Type: "container"}, line: "no timestamp"},
+	}
+
+	req := buildLokiPushRequest(entries)
+
+	if len(req.Streams) != 1 || len(req.Streams[0].Values) != 1 {
+		t.Fatalf("unexpected request shape: %#v", req)
+	}
+	if req.Streams[0].Values[0][0] == "0" {
+		t.Errorf("zero-time entry was pushed with a zero timestamp instead of falling back to now")
+	}
+}